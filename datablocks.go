@@ -0,0 +1,551 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// GUID is a 16-byte Globally Unique Identifier as laid out on disk per
+// MS-DTYP section 2.3.4.
+type GUID [16]byte
+
+// String returns the canonical "{XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX}"
+// representation of the GUID.
+func (g GUID) String() string {
+	return fmt.Sprintf("{%08X-%04X-%04X-%02X%02X-%02X%02X%02X%02X%02X%02X}",
+		binary.LittleEndian.Uint32(g[0:4]),
+		binary.LittleEndian.Uint16(g[4:6]),
+		binary.LittleEndian.Uint16(g[6:8]),
+		g[8], g[9], g[10], g[11], g[12], g[13], g[14], g[15])
+}
+
+// readGUID copies a 16-byte GUID out of b. Callers must ensure len(b) >= 16.
+func readGUID(b []byte) GUID {
+	var g GUID
+	copy(g[:], b)
+	return g
+}
+
+// COORD is a console X/Y coordinate pair, as used by ConsoleDataBlock
+// (MS-SHLLNK section 2.5.1).
+type COORD struct {
+	X int16 `json:"x"`
+	Y int16 `json:"y"`
+}
+
+func (c COORD) String() string {
+	return fmt.Sprintf("(%d,%d)", c.X, c.Y)
+}
+
+// indexUint16 returns the index of the first occurrence of v in u, or -1.
+func indexUint16(u []uint16, v uint16) int {
+	for i, x := range u {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// utf16ToString decodes u, a UTF-16LE code unit slice, trimming at the first
+// NUL.
+func utf16ToString(u []uint16) string {
+	if i := indexUint16(u, 0); i >= 0 {
+		u = u[:i]
+	}
+	return string(utf16.Decode(u))
+}
+
+// decodeANSIString trims a fixed-size, NUL-terminated ANSI byte buffer.
+func decodeANSIString(b []byte) string {
+	if i := bytes.IndexByte(b, 0x00); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// decodeUnicodeString trims a fixed-size, NUL-terminated UTF-16LE buffer.
+func decodeUnicodeString(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[i*2 : i*2+2])
+	}
+	return utf16ToString(u)
+}
+
+// decodeAnsiUnicodePair decodes the 260-byte ANSI / 520-byte Unicode string
+// pair shared by DarwinDataBlock, EnvironmentVariableDataBlock, and
+// IconEnvironmentDataBlock (MS-SHLLNK sections 2.5.3, 2.5.4, 2.5.7).
+func decodeAnsiUnicodePair(data []byte) (ansi, unicode string, err error) {
+	if len(data) < 260+520 {
+		return "", "", fmt.Errorf("short buffer (%d bytes)", len(data))
+	}
+	return decodeANSIString(data[0:260]), decodeUnicodeString(data[260 : 260+520]), nil
+}
+
+// filetimeToTime converts a Windows FILETIME (100ns intervals since
+// 1601-01-01) to a time.Time in UTC.
+func filetimeToTime(ft uint64) time.Time {
+	const epochDiff = 116444736000000000 // 100ns intervals between 1601 and 1970.
+	if ft < epochDiff {
+		return time.Time{}
+	}
+	return time.Unix(0, int64((ft-epochDiff)*100)).UTC()
+}
+
+// decodeExtraDataBlockObject parses the signature-specific payload of an
+// ExtraDataBlock (the Size/Signature header already consumed) into a typed
+// Object, per the per-signature layouts in MS-SHLLNK section 2.5. It returns
+// a nil Object, nil error for signatures without a decoder.
+func decodeExtraDataBlockObject(sig uint32, data []byte) (interface{}, error) {
+	switch sig {
+	case 0xA0000002:
+		return decodeConsoleDataBlock(data)
+	case 0xA0000004:
+		return decodeConsoleFEDataBlock(data)
+	case 0xA0000006:
+		return decodeDarwinDataBlock(data)
+	case 0xA0000001:
+		return decodeEnvironmentVariableDataBlock(data)
+	case 0xA0000007:
+		return decodeIconEnvironmentDataBlock(data)
+	case 0xA0000009:
+		return decodePropertyStoreDataBlock(data)
+	case 0xA0000008:
+		return decodeShimDataBlock(data)
+	case 0xA0000005:
+		return decodeSpecialFolderDataBlock(data)
+	case 0xA0000003:
+		return decodeTrackerDataBlock(data)
+	case 0xA000000C:
+		return decodeVistaAndAboveIDListDataBlock(data)
+	case 0xA000000B:
+		return decodeKnownFolderDataBlock(data)
+	default:
+		return nil, nil
+	}
+}
+
+// ConsoleDataBlock holds console window properties (MS-SHLLNK section
+// 2.5.1).
+type ConsoleDataBlock struct {
+	FillAttributes         uint16
+	PopupFillAttributes    uint16
+	ScreenBufferSize       COORD
+	WindowSize             COORD
+	WindowOrigin           COORD
+	FontSize               uint32
+	FontFamily             uint32
+	FontWeight             uint32
+	FaceName               [32]uint16
+	CursorSize             uint32
+	FullScreen             uint32
+	QuickEdit              uint32
+	InsertMode             uint32
+	AutoPosition           uint32
+	HistoryBufferSize      uint32
+	NumberOfHistoryBuffers uint32
+	HistoryNoDup           uint32
+	ColorTable             [16]uint32
+}
+
+// FaceNameString returns the console font face name, trimmed at the first
+// NUL.
+func (db ConsoleDataBlock) FaceNameString() string {
+	return utf16ToString(db.FaceName[:])
+}
+
+func (db ConsoleDataBlock) String() string {
+	return fmt.Sprintf("ConsoleDataBlock{FillAttributes: 0x%04X, FaceName: %q, FontSize: %d, ScreenBufferSize: %s, WindowSize: %s}",
+		db.FillAttributes, db.FaceNameString(), db.FontSize, db.ScreenBufferSize, db.WindowSize)
+}
+
+func decodeConsoleDataBlock(data []byte) (*ConsoleDataBlock, error) {
+	r := bytes.NewReader(data)
+	var db ConsoleDataBlock
+	var unused1, unused2 uint32
+	fields := []interface{}{
+		&db.FillAttributes, &db.PopupFillAttributes,
+		&db.ScreenBufferSize.X, &db.ScreenBufferSize.Y,
+		&db.WindowSize.X, &db.WindowSize.Y,
+		&db.WindowOrigin.X, &db.WindowOrigin.Y,
+		&unused1, &unused2,
+		&db.FontSize, &db.FontFamily, &db.FontWeight,
+		&db.FaceName,
+		&db.CursorSize, &db.FullScreen, &db.QuickEdit, &db.InsertMode,
+		&db.AutoPosition, &db.HistoryBufferSize, &db.NumberOfHistoryBuffers,
+		&db.HistoryNoDup, &db.ColorTable,
+	}
+	for _, f := range fields {
+		if err := binary.Read(r, binary.LittleEndian, f); err != nil {
+			return nil, fmt.Errorf("golnk.decodeConsoleDataBlock: %s", err.Error())
+		}
+	}
+	return &db, nil
+}
+
+// ConsoleFEDataBlock holds the console's code page (MS-SHLLNK section
+// 2.5.2).
+type ConsoleFEDataBlock struct {
+	CodePage uint32 `json:"code_page"`
+}
+
+func (db ConsoleFEDataBlock) String() string {
+	return fmt.Sprintf("ConsoleFEDataBlock{CodePage: %d}", db.CodePage)
+}
+
+func decodeConsoleFEDataBlock(data []byte) (*ConsoleFEDataBlock, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("golnk.decodeConsoleFEDataBlock: short buffer (%d bytes)", len(data))
+	}
+	return &ConsoleFEDataBlock{CodePage: binary.LittleEndian.Uint32(data[0:4])}, nil
+}
+
+// DarwinDataBlock holds an application's Darwin Application Identifier
+// (MS-SHLLNK section 2.5.3).
+type DarwinDataBlock struct {
+	DarwinDataAnsi    string `json:"darwin_data_ansi"`
+	DarwinDataUnicode string `json:"darwin_data_unicode"`
+}
+
+func (db DarwinDataBlock) String() string {
+	return fmt.Sprintf("DarwinDataBlock{DarwinDataAnsi: %q, DarwinDataUnicode: %q}", db.DarwinDataAnsi, db.DarwinDataUnicode)
+}
+
+func decodeDarwinDataBlock(data []byte) (*DarwinDataBlock, error) {
+	ansi, unicode, err := decodeAnsiUnicodePair(data)
+	if err != nil {
+		return nil, fmt.Errorf("golnk.decodeDarwinDataBlock: %s", err)
+	}
+	return &DarwinDataBlock{DarwinDataAnsi: ansi, DarwinDataUnicode: unicode}, nil
+}
+
+// EnvironmentVariableDataBlock holds a path to an environment-variable
+// expandable string (MS-SHLLNK section 2.5.4).
+type EnvironmentVariableDataBlock struct {
+	TargetAnsi    string `json:"target_ansi"`
+	TargetUnicode string `json:"target_unicode"`
+}
+
+func (db EnvironmentVariableDataBlock) String() string {
+	return fmt.Sprintf("EnvironmentVariableDataBlock{TargetAnsi: %q, TargetUnicode: %q}", db.TargetAnsi, db.TargetUnicode)
+}
+
+func decodeEnvironmentVariableDataBlock(data []byte) (*EnvironmentVariableDataBlock, error) {
+	ansi, unicode, err := decodeAnsiUnicodePair(data)
+	if err != nil {
+		return nil, fmt.Errorf("golnk.decodeEnvironmentVariableDataBlock: %s", err)
+	}
+	return &EnvironmentVariableDataBlock{TargetAnsi: ansi, TargetUnicode: unicode}, nil
+}
+
+// IconEnvironmentDataBlock holds a path to an environment-variable
+// expandable icon location (MS-SHLLNK section 2.5.7).
+type IconEnvironmentDataBlock struct {
+	TargetAnsi    string `json:"target_ansi"`
+	TargetUnicode string `json:"target_unicode"`
+}
+
+func (db IconEnvironmentDataBlock) String() string {
+	return fmt.Sprintf("IconEnvironmentDataBlock{TargetAnsi: %q, TargetUnicode: %q}", db.TargetAnsi, db.TargetUnicode)
+}
+
+func decodeIconEnvironmentDataBlock(data []byte) (*IconEnvironmentDataBlock, error) {
+	ansi, unicode, err := decodeAnsiUnicodePair(data)
+	if err != nil {
+		return nil, fmt.Errorf("golnk.decodeIconEnvironmentDataBlock: %s", err)
+	}
+	return &IconEnvironmentDataBlock{TargetAnsi: ansi, TargetUnicode: unicode}, nil
+}
+
+// ShimDataBlock names a shim layer applied to the target (MS-SHLLNK section
+// 2.5.8).
+type ShimDataBlock struct {
+	LayerName string `json:"layer_name"`
+}
+
+func (db ShimDataBlock) String() string {
+	return fmt.Sprintf("ShimDataBlock{LayerName: %q}", db.LayerName)
+}
+
+func decodeShimDataBlock(data []byte) (*ShimDataBlock, error) {
+	return &ShimDataBlock{LayerName: decodeUnicodeString(data)}, nil
+}
+
+// SpecialFolderDataBlock locates the target within a special folder
+// (MS-SHLLNK section 2.5.9).
+type SpecialFolderDataBlock struct {
+	SpecialFolderID uint32 `json:"special_folder_id"`
+	Offset          uint32 `json:"offset"`
+}
+
+func (db SpecialFolderDataBlock) String() string {
+	return fmt.Sprintf("SpecialFolderDataBlock{SpecialFolderID: %d, Offset: %d}", db.SpecialFolderID, db.Offset)
+}
+
+func decodeSpecialFolderDataBlock(data []byte) (*SpecialFolderDataBlock, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("golnk.decodeSpecialFolderDataBlock: short buffer (%d bytes)", len(data))
+	}
+	return &SpecialFolderDataBlock{
+		SpecialFolderID: binary.LittleEndian.Uint32(data[0:4]),
+		Offset:          binary.LittleEndian.Uint32(data[4:8]),
+	}, nil
+}
+
+// TrackerDataBlock holds the distributed link tracking properties
+// (MS-SHLLNK section 2.5.10).
+type TrackerDataBlock struct {
+	Length     uint32  `json:"length"`
+	Version    uint32  `json:"version"`
+	MachineID  string  `json:"machine_id"`
+	Droid      [2]GUID `json:"droid"`
+	DroidBirth [2]GUID `json:"droid_birth"`
+}
+
+func (db TrackerDataBlock) String() string {
+	return fmt.Sprintf("TrackerDataBlock{MachineID: %q, Droid: [%s, %s], DroidBirth: [%s, %s]}",
+		db.MachineID, db.Droid[0], db.Droid[1], db.DroidBirth[0], db.DroidBirth[1])
+}
+
+func decodeTrackerDataBlock(data []byte) (*TrackerDataBlock, error) {
+	if len(data) < 88 {
+		return nil, fmt.Errorf("golnk.decodeTrackerDataBlock: short buffer (%d bytes)", len(data))
+	}
+	db := &TrackerDataBlock{
+		Length:    binary.LittleEndian.Uint32(data[0:4]),
+		Version:   binary.LittleEndian.Uint32(data[4:8]),
+		MachineID: decodeANSIString(data[8:24]),
+	}
+	db.Droid[0] = readGUID(data[24:40])
+	db.Droid[1] = readGUID(data[40:56])
+	db.DroidBirth[0] = readGUID(data[56:72])
+	db.DroidBirth[1] = readGUID(data[72:88])
+	return db, nil
+}
+
+// VistaAndAboveIDListDataBlock holds an alternate IDList for Windows Vista
+// and later (MS-SHLLNK section 2.5.11). Item-by-item shell ID parsing is not
+// implemented yet, so IDList keeps the raw, undecoded list.
+type VistaAndAboveIDListDataBlock struct {
+	IDList []byte `json:"id_list"`
+}
+
+func (db VistaAndAboveIDListDataBlock) String() string {
+	return fmt.Sprintf("VistaAndAboveIDListDataBlock{%d bytes}", len(db.IDList))
+}
+
+func decodeVistaAndAboveIDListDataBlock(data []byte) (*VistaAndAboveIDListDataBlock, error) {
+	idList := make([]byte, len(data))
+	copy(idList, data)
+	return &VistaAndAboveIDListDataBlock{IDList: idList}, nil
+}
+
+// KnownFolderDataBlock locates the target within a known folder
+// (MS-SHLLNK section 2.5.6).
+type KnownFolderDataBlock struct {
+	KnownFolderID GUID   `json:"known_folder_id"`
+	Offset        uint32 `json:"offset"`
+}
+
+func (db KnownFolderDataBlock) String() string {
+	return fmt.Sprintf("KnownFolderDataBlock{KnownFolderID: %s, Offset: %d}", db.KnownFolderID, db.Offset)
+}
+
+func decodeKnownFolderDataBlock(data []byte) (*KnownFolderDataBlock, error) {
+	if len(data) < 20 {
+		return nil, fmt.Errorf("golnk.decodeKnownFolderDataBlock: short buffer (%d bytes)", len(data))
+	}
+	return &KnownFolderDataBlock{
+		KnownFolderID: readGUID(data[0:16]),
+		Offset:        binary.LittleEndian.Uint32(data[16:20]),
+	}, nil
+}
+
+// fmtidStringNamed is the FormatID ({D5CDD505-2E9C-101B-9397-08002B2CFC9A})
+// that marks a SerializedPropertyStore as using string property names rather
+// than numeric property IDs (MS-PROPSTORE section 2.2, MS-OLEPS FMTID
+// PID_STG).
+var fmtidStringNamed = GUID{0x05, 0xD5, 0xCD, 0xD5, 0x9C, 0x2E, 0x1B, 0x10, 0x93, 0x97, 0x08, 0x00, 0x2B, 0x2C, 0xFC, 0x9A}
+
+// SerializedPropertyValue is a single named or numbered property within a
+// SerializedPropertyStore (MS-PROPSTORE sections 2.3, 2.4).
+type SerializedPropertyValue struct {
+	// Name is set when the owning store uses string property names.
+	Name string
+	// ID is set when the owning store uses numeric property IDs.
+	ID uint32
+	// VarType is the VT_* variant type tag describing Value.
+	VarType uint16
+	// Value holds the decoded value for known VarTypes, or the raw payload
+	// bytes when VarType has no decoder.
+	Value interface{}
+}
+
+// SerializedPropertyStore is one "Serialized Property Storage" structure
+// embedded in a PropertyStoreDataBlock (MS-PROPSTORE section 2.2).
+type SerializedPropertyStore struct {
+	Version  uint32                    `json:"version"`
+	FormatID GUID                      `json:"format_id"`
+	Values   []SerializedPropertyValue `json:"values"`
+}
+
+// PropertyStoreDataBlock holds a list of serialized property stores
+// (MS-SHLLNK section 2.5.8 / MS-PROPSTORE).
+type PropertyStoreDataBlock struct {
+	Stores []SerializedPropertyStore `json:"stores"`
+}
+
+func (db PropertyStoreDataBlock) String() string {
+	var sb strings.Builder
+	for _, store := range db.Stores {
+		sb.WriteString(fmt.Sprintf("PropertyStore{FormatID: %s, Values: %d}\n", store.FormatID, len(store.Values)))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// MS-OLEPS VARTYPE tags decoded by decodeVariantValue.
+const (
+	vtEmpty    = 0x0000
+	vtI2       = 0x0002
+	vtI4       = 0x0003
+	vtBool     = 0x000B
+	vtLPWSTR   = 0x001F
+	vtUI4      = 0x0013
+	vtUI8      = 0x0015
+	vtFiletime = 0x0040
+	vtClsid    = 0x0048
+)
+
+func decodePropertyStoreDataBlock(data []byte) (*PropertyStoreDataBlock, error) {
+	var psdb PropertyStoreDataBlock
+	for len(data) >= 4 {
+		storageSize := binary.LittleEndian.Uint32(data[0:4])
+		if storageSize == 0 {
+			break // Terminating empty SerializedPropertyStore.
+		}
+		if int(storageSize) > len(data)-4 {
+			return nil, fmt.Errorf("golnk.decodePropertyStoreDataBlock: storage size %d exceeds remaining %d bytes", storageSize, len(data)-4)
+		}
+		storeBuf := data[4 : 4+storageSize]
+		data = data[4+storageSize:]
+
+		if len(storeBuf) < 20 {
+			return nil, fmt.Errorf("golnk.decodePropertyStoreDataBlock: short store header (%d bytes)", len(storeBuf))
+		}
+		store := SerializedPropertyStore{
+			Version:  binary.LittleEndian.Uint32(storeBuf[0:4]),
+			FormatID: readGUID(storeBuf[4:20]),
+		}
+		named := store.FormatID == fmtidStringNamed
+
+		rest := storeBuf[20:]
+		for len(rest) >= 4 {
+			valueSize := binary.LittleEndian.Uint32(rest[0:4])
+			if valueSize == 0 {
+				break // Terminating empty SerializedPropertyValue.
+			}
+			if int(valueSize) > len(rest)-4 {
+				return nil, fmt.Errorf("golnk.decodePropertyStoreDataBlock: value size %d exceeds remaining %d bytes", valueSize, len(rest)-4)
+			}
+			valBuf := rest[4 : 4+valueSize]
+			rest = rest[4+valueSize:]
+
+			pv, err := decodeSerializedPropertyValue(valBuf, named)
+			if err != nil {
+				return nil, fmt.Errorf("golnk.decodePropertyStoreDataBlock: %s", err)
+			}
+			store.Values = append(store.Values, *pv)
+		}
+		psdb.Stores = append(psdb.Stores, store)
+	}
+	return &psdb, nil
+}
+
+// decodeSerializedPropertyValue decodes one Serialized Property Value (Name)
+// or Serialized Property Value (ID) structure, not including its leading
+// ValueSize field (MS-PROPSTORE sections 2.3, 2.4).
+func decodeSerializedPropertyValue(buf []byte, named bool) (*SerializedPropertyValue, error) {
+	pv := &SerializedPropertyValue{}
+	var off int
+	if named {
+		if len(buf) < 4 {
+			return nil, fmt.Errorf("short named property value")
+		}
+		nameSize := binary.LittleEndian.Uint32(buf[0:4])
+		off = 4
+		if int(nameSize) > len(buf)-off {
+			return nil, fmt.Errorf("name size %d exceeds buffer", nameSize)
+		}
+		pv.Name = decodeUnicodeString(buf[off : off+int(nameSize)])
+		off += int(nameSize)
+	} else {
+		if len(buf) < 4 {
+			return nil, fmt.Errorf("short id property value")
+		}
+		pv.ID = binary.LittleEndian.Uint32(buf[0:4])
+		off = 4
+	}
+	if off >= len(buf) {
+		return nil, fmt.Errorf("missing Reserved byte")
+	}
+	off++ // Reserved, must be 0xA1.
+	if off+4 > len(buf) {
+		return nil, fmt.Errorf("missing TypedPropertyValue header")
+	}
+	pv.VarType = binary.LittleEndian.Uint16(buf[off : off+2])
+	off += 4 // VarType (2 bytes) + Padding (2 bytes).
+	pv.Value = decodeVariantValue(pv.VarType, buf[off:])
+	return pv, nil
+}
+
+// decodeVariantValue decodes the value half of a TypedPropertyValue for the
+// common VT_* tags used in .lnk property stores, falling back to the raw
+// bytes for anything else.
+func decodeVariantValue(vt uint16, data []byte) interface{} {
+	switch vt {
+	case vtEmpty:
+		return nil
+	case vtI2:
+		if len(data) >= 2 {
+			return int16(binary.LittleEndian.Uint16(data))
+		}
+	case vtBool:
+		if len(data) >= 2 {
+			return binary.LittleEndian.Uint16(data) != 0
+		}
+	case vtI4:
+		if len(data) >= 4 {
+			return int32(binary.LittleEndian.Uint32(data))
+		}
+	case vtUI4:
+		if len(data) >= 4 {
+			return binary.LittleEndian.Uint32(data)
+		}
+	case vtUI8:
+		if len(data) >= 8 {
+			return binary.LittleEndian.Uint64(data)
+		}
+	case vtFiletime:
+		if len(data) >= 8 {
+			return filetimeToTime(binary.LittleEndian.Uint64(data))
+		}
+	case vtClsid:
+		if len(data) >= 16 {
+			return readGUID(data[:16])
+		}
+	case vtLPWSTR:
+		if len(data) >= 4 {
+			count := binary.LittleEndian.Uint32(data[0:4])
+			end := 4 + int(count)*2
+			if end <= len(data) {
+				return decodeUnicodeString(data[4:end])
+			}
+		}
+	}
+	return data
+}
@@ -0,0 +1,34 @@
+package lnk
+
+import "testing"
+
+func TestDecodeConsoleFEDataBlock(t *testing.T) {
+	data := []byte{0xB5, 0x01, 0x00, 0x00} // CodePage = 437
+	db, err := decodeConsoleFEDataBlock(data)
+	if err != nil {
+		t.Fatalf("decodeConsoleFEDataBlock: %v", err)
+	}
+	if db.CodePage != 437 {
+		t.Errorf("CodePage = %d, want 437", db.CodePage)
+	}
+}
+
+func TestDecodeSpecialFolderDataBlock(t *testing.T) {
+	data := []byte{
+		0x05, 0x00, 0x00, 0x00, // SpecialFolderID = 5
+		0x10, 0x00, 0x00, 0x00, // Offset = 16
+	}
+	db, err := decodeSpecialFolderDataBlock(data)
+	if err != nil {
+		t.Fatalf("decodeSpecialFolderDataBlock: %v", err)
+	}
+	if db.SpecialFolderID != 5 || db.Offset != 16 {
+		t.Errorf("got %+v, want {SpecialFolderID:5 Offset:16}", db)
+	}
+}
+
+func TestDecodeTrackerDataBlockShortBuffer(t *testing.T) {
+	if _, err := decodeTrackerDataBlock(make([]byte, 10)); err == nil {
+		t.Error("expected error for a short buffer, got nil")
+	}
+}
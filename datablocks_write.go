@@ -0,0 +1,302 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+	"unicode/utf16"
+)
+
+// encodeExtraDataBlockObject re-serializes a typed block Object into its
+// signature-specific payload bytes. It is the mirror of
+// decodeExtraDataBlockObject.
+func encodeExtraDataBlockObject(obj interface{}) ([]byte, error) {
+	switch v := obj.(type) {
+	case *ConsoleDataBlock:
+		return encodeConsoleDataBlock(v)
+	case *ConsoleFEDataBlock:
+		return encodeConsoleFEDataBlock(v), nil
+	case *DarwinDataBlock:
+		return encodeAnsiUnicodePair(v.DarwinDataAnsi, v.DarwinDataUnicode), nil
+	case *EnvironmentVariableDataBlock:
+		return encodeAnsiUnicodePair(v.TargetAnsi, v.TargetUnicode), nil
+	case *IconEnvironmentDataBlock:
+		return encodeAnsiUnicodePair(v.TargetAnsi, v.TargetUnicode), nil
+	case *PropertyStoreDataBlock:
+		return encodePropertyStoreDataBlock(v)
+	case *ShimDataBlock:
+		return encodeUnicodeStringNoPad(v.LayerName), nil
+	case *SpecialFolderDataBlock:
+		return encodeSpecialFolderDataBlock(v), nil
+	case *TrackerDataBlock:
+		return encodeTrackerDataBlock(v), nil
+	case *VistaAndAboveIDListDataBlock:
+		return append([]byte(nil), v.IDList...), nil
+	case *KnownFolderDataBlock:
+		return encodeKnownFolderDataBlock(v), nil
+	default:
+		return nil, fmt.Errorf("no encoder registered for %T", obj)
+	}
+}
+
+// encodeANSIBuffer renders s into a fixed-size, NUL-padded ANSI buffer,
+// truncating s if necessary to leave room for the terminating NUL.
+func encodeANSIBuffer(s string, size int) []byte {
+	buf := make([]byte, size)
+	b := []byte(s)
+	if len(b) > size-1 {
+		b = b[:size-1]
+	}
+	copy(buf, b)
+	return buf
+}
+
+// encodeUnicodeBuffer renders s into a fixed-size, NUL-padded UTF-16LE
+// buffer, truncating s if necessary to leave room for the terminating NUL.
+func encodeUnicodeBuffer(s string, size int) []byte {
+	buf := make([]byte, size)
+	u := utf16.Encode([]rune(s))
+	maxUnits := size/2 - 1
+	if len(u) > maxUnits {
+		u = u[:maxUnits]
+	}
+	for i, v := range u {
+		binary.LittleEndian.PutUint16(buf[i*2:i*2+2], v)
+	}
+	return buf
+}
+
+// encodeUnicodeStringNoPad renders s as an unpadded UTF-16LE byte sequence.
+func encodeUnicodeStringNoPad(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	buf := make([]byte, len(u)*2)
+	for i, v := range u {
+		binary.LittleEndian.PutUint16(buf[i*2:i*2+2], v)
+	}
+	return buf
+}
+
+// encodeAnsiUnicodePair renders the 260-byte ANSI / 520-byte Unicode string
+// pair shared by DarwinDataBlock, EnvironmentVariableDataBlock, and
+// IconEnvironmentDataBlock.
+func encodeAnsiUnicodePair(ansi, unicode string) []byte {
+	buf := make([]byte, 0, 260+520)
+	buf = append(buf, encodeANSIBuffer(ansi, 260)...)
+	buf = append(buf, encodeUnicodeBuffer(unicode, 520)...)
+	return buf
+}
+
+// timeToFiletime converts t to a Windows FILETIME (100ns intervals since
+// 1601-01-01), the inverse of filetimeToTime.
+func timeToFiletime(t time.Time) uint64 {
+	const epochDiff = 116444736000000000 // 100ns intervals between 1601 and 1970.
+	return uint64(t.UnixNano()/100) + epochDiff
+}
+
+func encodeConsoleDataBlock(db *ConsoleDataBlock) ([]byte, error) {
+	var buf bytes.Buffer
+	fields := []interface{}{
+		db.FillAttributes, db.PopupFillAttributes,
+		db.ScreenBufferSize.X, db.ScreenBufferSize.Y,
+		db.WindowSize.X, db.WindowSize.Y,
+		db.WindowOrigin.X, db.WindowOrigin.Y,
+		uint32(0), uint32(0), // Unused1, Unused2.
+		db.FontSize, db.FontFamily, db.FontWeight,
+		db.FaceName,
+		db.CursorSize, db.FullScreen, db.QuickEdit, db.InsertMode,
+		db.AutoPosition, db.HistoryBufferSize, db.NumberOfHistoryBuffers,
+		db.HistoryNoDup, db.ColorTable,
+	}
+	for _, f := range fields {
+		if err := binary.Write(&buf, binary.LittleEndian, f); err != nil {
+			return nil, fmt.Errorf("golnk.encodeConsoleDataBlock: %s", err.Error())
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeConsoleFEDataBlock(db *ConsoleFEDataBlock) []byte {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, db.CodePage)
+	return buf
+}
+
+func encodeSpecialFolderDataBlock(db *SpecialFolderDataBlock) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint32(buf[0:4], db.SpecialFolderID)
+	binary.LittleEndian.PutUint32(buf[4:8], db.Offset)
+	return buf
+}
+
+func encodeTrackerDataBlock(db *TrackerDataBlock) []byte {
+	buf := make([]byte, 88)
+	binary.LittleEndian.PutUint32(buf[0:4], db.Length)
+	binary.LittleEndian.PutUint32(buf[4:8], db.Version)
+	copy(buf[8:24], encodeANSIBuffer(db.MachineID, 16))
+	copy(buf[24:40], db.Droid[0][:])
+	copy(buf[40:56], db.Droid[1][:])
+	copy(buf[56:72], db.DroidBirth[0][:])
+	copy(buf[72:88], db.DroidBirth[1][:])
+	return buf
+}
+
+func encodeKnownFolderDataBlock(db *KnownFolderDataBlock) []byte {
+	buf := make([]byte, 20)
+	copy(buf[0:16], db.KnownFolderID[:])
+	binary.LittleEndian.PutUint32(buf[16:20], db.Offset)
+	return buf
+}
+
+// encodePropertyStoreDataBlock re-serializes each SerializedPropertyStore,
+// terminating it with an empty SerializedPropertyValue, then terminates the
+// whole block with an empty SerializedPropertyStore (MS-PROPSTORE section
+// 2.2).
+func encodePropertyStoreDataBlock(db *PropertyStoreDataBlock) ([]byte, error) {
+	var out bytes.Buffer
+	for _, store := range db.Stores {
+		var sbuf bytes.Buffer
+		if err := binary.Write(&sbuf, binary.LittleEndian, store.Version); err != nil {
+			return nil, fmt.Errorf("golnk.encodePropertyStoreDataBlock: %s", err.Error())
+		}
+		sbuf.Write(store.FormatID[:])
+		named := store.FormatID == fmtidStringNamed
+
+		for _, val := range store.Values {
+			vbuf, err := encodeSerializedPropertyValue(val, named)
+			if err != nil {
+				return nil, fmt.Errorf("golnk.encodePropertyStoreDataBlock: %s", err.Error())
+			}
+			if err := binary.Write(&sbuf, binary.LittleEndian, uint32(len(vbuf))); err != nil {
+				return nil, fmt.Errorf("golnk.encodePropertyStoreDataBlock: %s", err.Error())
+			}
+			sbuf.Write(vbuf)
+		}
+		if err := binary.Write(&sbuf, binary.LittleEndian, uint32(0)); err != nil {
+			return nil, fmt.Errorf("golnk.encodePropertyStoreDataBlock: %s", err.Error())
+		}
+
+		if err := binary.Write(&out, binary.LittleEndian, uint32(sbuf.Len())); err != nil {
+			return nil, fmt.Errorf("golnk.encodePropertyStoreDataBlock: %s", err.Error())
+		}
+		out.Write(sbuf.Bytes())
+	}
+	if err := binary.Write(&out, binary.LittleEndian, uint32(0)); err != nil {
+		return nil, fmt.Errorf("golnk.encodePropertyStoreDataBlock: %s", err.Error())
+	}
+	return out.Bytes(), nil
+}
+
+// encodeSerializedPropertyValue re-serializes one SerializedPropertyValue,
+// not including its leading ValueSize field (the caller writes that, since
+// it needs the encoded length).
+func encodeSerializedPropertyValue(val SerializedPropertyValue, named bool) ([]byte, error) {
+	var buf bytes.Buffer
+	if named {
+		nameBuf := append(encodeUnicodeStringNoPad(val.Name), 0, 0)
+		if err := binary.Write(&buf, binary.LittleEndian, uint32(len(nameBuf))); err != nil {
+			return nil, err
+		}
+		buf.Write(nameBuf)
+	} else {
+		if err := binary.Write(&buf, binary.LittleEndian, val.ID); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte(0xA1) // Reserved.
+
+	valBuf, err := encodeVariantValue(val.VarType, val.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, val.VarType); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, uint16(0)); err != nil { // Padding.
+		return nil, err
+	}
+	buf.Write(valBuf)
+	return buf.Bytes(), nil
+}
+
+// encodeVariantValue is the mirror of decodeVariantValue: it re-encodes the
+// value half of a TypedPropertyValue for the common VT_* tags, requiring
+// Value to hold the corresponding Go type.
+func encodeVariantValue(vt uint16, value interface{}) ([]byte, error) {
+	switch vt {
+	case vtEmpty:
+		return nil, nil
+	case vtI2:
+		v, ok := value.(int16)
+		if !ok {
+			return nil, fmt.Errorf("VT_I2 value must be int16, got %T", value)
+		}
+		buf := make([]byte, 2)
+		binary.LittleEndian.PutUint16(buf, uint16(v))
+		return buf, nil
+	case vtBool:
+		v, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("VT_BOOL value must be bool, got %T", value)
+		}
+		buf := make([]byte, 2)
+		if v {
+			binary.LittleEndian.PutUint16(buf, 0xFFFF)
+		}
+		return buf, nil
+	case vtI4:
+		v, ok := value.(int32)
+		if !ok {
+			return nil, fmt.Errorf("VT_I4 value must be int32, got %T", value)
+		}
+		buf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(buf, uint32(v))
+		return buf, nil
+	case vtUI4:
+		v, ok := value.(uint32)
+		if !ok {
+			return nil, fmt.Errorf("VT_UI4 value must be uint32, got %T", value)
+		}
+		buf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(buf, v)
+		return buf, nil
+	case vtUI8:
+		v, ok := value.(uint64)
+		if !ok {
+			return nil, fmt.Errorf("VT_UI8 value must be uint64, got %T", value)
+		}
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, v)
+		return buf, nil
+	case vtFiletime:
+		v, ok := value.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("VT_FILETIME value must be time.Time, got %T", value)
+		}
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, timeToFiletime(v))
+		return buf, nil
+	case vtClsid:
+		v, ok := value.(GUID)
+		if !ok {
+			return nil, fmt.Errorf("VT_CLSID value must be GUID, got %T", value)
+		}
+		return append([]byte(nil), v[:]...), nil
+	case vtLPWSTR:
+		v, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("VT_LPWSTR value must be string, got %T", value)
+		}
+		strBuf := append(encodeUnicodeStringNoPad(v), 0, 0)
+		buf := make([]byte, 4+len(strBuf))
+		binary.LittleEndian.PutUint32(buf[0:4], uint32(len(strBuf)/2))
+		copy(buf[4:], strBuf)
+		return buf, nil
+	default:
+		raw, ok := value.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("unsupported VarType 0x%04X value %T", vt, value)
+		}
+		return raw, nil
+	}
+}
@@ -1,6 +1,7 @@
 package lnk
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
@@ -9,11 +10,17 @@ import (
 )
 
 // ExtraDataSection represents section 2.5 of the specification.
+//
+// Blocks are scanned eagerly for their Size, Signature, and raw Data, but
+// each block's typed Object is decoded lazily -- and cached -- on first
+// access through Block, BlockByType, or Blocks. See File.ExtraData for a way
+// to obtain an ExtraDataSection without decoding the blocks you don't need.
 type ExtraDataSection struct {
-	Blocks []ExtraDataBlock
-	// Terminal block at the end of the ExtraData section.
+	// TerminalBlock at the end of the ExtraData section.
 	// Value must be smaller than 0x04.
 	TerminalBlock uint32
+
+	entries []ExtraDataBlock
 }
 
 /*
@@ -36,63 +43,177 @@ type ExtraDataBlock struct {
 	Object interface{}
 }
 
-// DataBlock reads and populates an ExtraData.
-func DataBlock(r io.Reader) (extra ExtraDataSection, err error) {
+// DataBlock reads and populates an ExtraDataSection, eagerly decoding every
+// block. It is implemented in terms of the lazy, io.ReaderAt-backed scan
+// used by File, so callers who only need a handful of blocks out of a large
+// directory of shortcuts should prefer File.ExtraData instead.
+func DataBlock(r io.Reader) (ExtraDataSection, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return ExtraDataSection{}, fmt.Errorf("golnk.DataBlock: read - %s", err.Error())
+	}
+
+	extra, err := scanExtraDataSection(bytes.NewReader(data), 0)
+	if err != nil {
+		return extra, err
+	}
+	// A block whose Object fails to decode is still kept, with Object left
+	// nil, matching Blocks(); only the block framing (Size/Signature) above
+	// is load-bearing enough to abort the whole parse.
+	for i := range extra.entries {
+		_ = extra.decode(i)
+	}
+	return extra, nil
+}
+
+// maxExtraDataBlockSize bounds the allocation scanExtraDataSection makes for
+// a block's attacker-controlled Size field, guarding against a crafted .lnk
+// with a Size near 0xFFFFFFFF forcing a multi-gigabyte allocation (a Size of
+// 4-7, which would underflow size-8, is rejected by a separate check) --
+// the same class of issue
+// ParseOptions.MaxBlockSize guards against in parseExtraDataSection, just
+// unguarded on this eager/lazy shared path. Real ExtraDataBlocks are at most
+// a few KiB.
+const maxExtraDataBlockSize = 1 << 20 // 1 MiB.
 
-	var db ExtraDataBlock
+// scanExtraDataSection walks r starting at off, recording each block's
+// Size, Signature, and Data without decoding Object, until it reaches the
+// TerminalBlock.
+func scanExtraDataSection(r io.ReaderAt, off int64) (ExtraDataSection, error) {
+	var extra ExtraDataSection
 	for {
-		// Read size.
-		var size uint32
-		err = binary.Read(r, binary.LittleEndian, &size)
-		if err != nil {
-			return extra, fmt.Errorf("golnk.readDataBlock: read size - %s", err.Error())
+		var sizeBuf [4]byte
+		if _, err := r.ReadAt(sizeBuf[:], off); err != nil {
+			return extra, fmt.Errorf("golnk.scanExtraDataSection: read size - %s", err.Error())
 		}
-		// fmt.Println("Size", size)
+		size := binary.LittleEndian.Uint32(sizeBuf[:])
+		off += 4
+
 		// Have we reached the TerminalBlock?
 		if size < 0x04 {
 			extra.TerminalBlock = size
-			break
+			return extra, nil
+		}
+		if size < 8 {
+			return extra, fmt.Errorf("golnk.scanExtraDataSection: block size 0x%X is smaller than the 8-byte block header", size)
+		}
+		if size > maxExtraDataBlockSize {
+			return extra, fmt.Errorf("golnk.scanExtraDataSection: block size 0x%X exceeds sanity limit of 0x%X", size, uint32(maxExtraDataBlockSize))
 		}
-		db.Size = size
 
-		// Read block's signature.
-		err = binary.Read(r, binary.LittleEndian, &db.Signature)
-		if err != nil {
-			return extra, fmt.Errorf("golnk.readDataBlock: read signature - %s", err.Error())
+		var sigBuf [4]byte
+		if _, err := r.ReadAt(sigBuf[:], off); err != nil {
+			return extra, fmt.Errorf("golnk.scanExtraDataSection: read signature - %s", err.Error())
 		}
-		// fmt.Println("Signature", hex.EncodeToString(uint32Byte(db.Signature)))
-		db.Type = blockSignature(db.Signature)
-		// fmt.Println("Type:", db.Type)
+		sig := binary.LittleEndian.Uint32(sigBuf[:])
+		off += 4
 
-		// Read the rest of the data. Size-8.
-		data := make([]byte, db.Size-8)
-		err = binary.Read(r, binary.LittleEndian, &data)
-		if err != nil {
-			return extra, fmt.Errorf("golnk.readDataBlock: read data - %s", err.Error())
+		data := make([]byte, size-8)
+		if len(data) > 0 {
+			if _, err := r.ReadAt(data, off); err != nil {
+				return extra, fmt.Errorf("golnk.scanExtraDataSection: read data - %s", err.Error())
+			}
 		}
-		db.Data = data
-		// fmt.Println(hex.Dump(data))
-		extra.Blocks = append(extra.Blocks, db)
+		off += int64(len(data))
+
+		extra.entries = append(extra.entries, ExtraDataBlock{
+			Size:      size,
+			Signature: sig,
+			Type:      blockSignature(sig),
+			Data:      data,
+		})
 	}
-	return extra, nil
+}
+
+// decode lazily decodes and caches entries[i].Object.
+func (e ExtraDataSection) decode(i int) error {
+	if e.entries[i].Object != nil {
+		return nil
+	}
+	obj, err := decodeExtraDataBlockObject(e.entries[i].Signature, e.entries[i].Data)
+	if err != nil {
+		return fmt.Errorf("golnk.ExtraDataSection: decode %s - %s", e.entries[i].Type, err.Error())
+	}
+	e.entries[i].Object = obj
+	return nil
+}
+
+// Blocks returns every block in the section, decoding (and caching) each
+// block's Object on first access. A block whose Object fails to decode is
+// still returned, with Object left nil.
+func (e ExtraDataSection) Blocks() []ExtraDataBlock {
+	for i := range e.entries {
+		_ = e.decode(i)
+	}
+	out := make([]ExtraDataBlock, len(e.entries))
+	copy(out, e.entries)
+	return out
+}
+
+// Block returns the first block with the given signature, decoding its
+// Object on demand, or nil if no such block exists.
+func (e ExtraDataSection) Block(sig uint32) *ExtraDataBlock {
+	for i := range e.entries {
+		if e.entries[i].Signature == sig {
+			_ = e.decode(i)
+			b := e.entries[i]
+			return &b
+		}
+	}
+	return nil
+}
+
+// BlockByType returns the first block whose Type matches name (e.g.
+// "TrackerDataBlock"), decoding its Object on demand, or nil if no such
+// block exists.
+func (e ExtraDataSection) BlockByType(name string) *ExtraDataBlock {
+	for i := range e.entries {
+		if e.entries[i].Type == name {
+			_ = e.decode(i)
+			b := e.entries[i]
+			return &b
+		}
+	}
+	return nil
+}
+
+// blockSignatureNames maps each known ExtraDataBlock signature to its
+// MS-SHLLNK section 2.5 type name.
+var blockSignatureNames = map[uint32]string{
+	0xA0000002: "ConsoleDataBlock",
+	0xA0000004: "ConsoleFEDataBlock",
+	0xA0000006: "DarwinDataBlock",
+	0xA0000001: "EnvironmentVariableDataBlock",
+	0xA0000007: "IconEnvironmentDataBlock",
+	0xA0000009: "PropertyStoreDataBlock",
+	0xA0000008: "ShimDataBlock",
+	0xA0000005: "SpecialFolderDataBlock",
+	0xA0000003: "TrackerDataBlock",
+	0xA000000C: "VistaAndAboveIDListDataBlock",
+	0xA000000B: "KnownFolderDataBlock",
+}
+
+// uint32Byte returns v's little-endian byte representation.
+func uint32Byte(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+// uint32StrHex formats v as a "0x%08X" hex string.
+func uint32StrHex(v uint32) string {
+	return fmt.Sprintf("0x%08X", v)
+}
+
+// uint32TableStr formats v for the Size line in String, as both decimal and
+// hex.
+func uint32TableStr(v uint32) string {
+	return fmt.Sprintf("%d (0x%08X)", v, v)
 }
 
 // blockSignature returns the block type based on signature.
 func blockSignature(sig uint32) string {
-	signatureMap := map[uint32]string{
-		0xA0000002: "ConsoleDataBlock",
-		0xA0000004: "ConsoleFEDataBlock",
-		0xA0000006: "DarwinDataBlock",
-		0xA0000001: "EnvironmentVariableDataBlock",
-		0xA0000007: "IconEnvironmentDataBlock",
-		0xA0000009: "PropertyStoreDataBlock",
-		0xA0000008: "ShimDataBlock",
-		0xA0000005: "SpecialFolderDataBlock",
-		0xA0000003: "TrackerDataBlock",
-		0xA000000C: "VistaAndAboveIDListDataBlock",
-		0xA000000B: "KnownFolderDataBlock",
-	}
-	if val, exists := signatureMap[sig]; exists {
+	if val, exists := blockSignatureNames[sig]; exists {
 		return val
 	}
 	return "Signature Not Found - " + hex.EncodeToString(uint32Byte(sig))
@@ -102,13 +223,12 @@ func blockSignature(sig uint32) string {
 func (e ExtraDataSection) String() string {
 
 	var sb strings.Builder
-	for _, b := range e.Blocks {
+	for _, b := range e.Blocks() {
 		sb.WriteString(fmt.Sprintf("Size: %s\n", uint32TableStr(b.Size)))
 		sb.WriteString(fmt.Sprintf("Signature: %s\n", uint32StrHex(b.Signature)))
 		sb.WriteString(fmt.Sprintf("Type: %s\n", b.Type))
-		sb.WriteString("Dump\n")
-		sb.WriteString(b.Dump())
-		sb.WriteString("-------------------------\n")
+		sb.WriteString(b.String())
+		sb.WriteString("\n-------------------------\n")
 	}
 	return sb.String()
 }
@@ -117,3 +237,63 @@ func (e ExtraDataSection) String() string {
 func (db ExtraDataBlock) Dump() string {
 	return hex.Dump(db.Data)
 }
+
+// String returns the typed Object's own String(), when a decoder exists for
+// this block's signature, or a hex dump of the raw payload otherwise.
+func (db ExtraDataBlock) String() string {
+	if s, ok := db.Object.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return db.Dump()
+}
+
+// WriteTo serializes the block back into its on-disk form: Size (uint32 LE),
+// Signature (uint32 LE), then the block's payload. When Object is set, the
+// payload is re-encoded from its fields, so mutating a typed Object (e.g.
+// TrackerDataBlock.MachineID) produces a valid block; otherwise the original
+// raw Data is written unchanged. Size is always recomputed from the encoded
+// payload.
+func (db ExtraDataBlock) WriteTo(w io.Writer) (int64, error) {
+	payload := db.Data
+	if db.Object != nil {
+		encoded, err := encodeExtraDataBlockObject(db.Object)
+		if err != nil {
+			return 0, fmt.Errorf("golnk.ExtraDataBlock.WriteTo: encode %s - %s", db.Type, err.Error())
+		}
+		payload = encoded
+	}
+
+	var written int64
+	if err := binary.Write(w, binary.LittleEndian, uint32(8+len(payload))); err != nil {
+		return written, fmt.Errorf("golnk.ExtraDataBlock.WriteTo: write size - %s", err.Error())
+	}
+	written += 4
+	if err := binary.Write(w, binary.LittleEndian, db.Signature); err != nil {
+		return written, fmt.Errorf("golnk.ExtraDataBlock.WriteTo: write signature - %s", err.Error())
+	}
+	written += 4
+	n, err := w.Write(payload)
+	written += int64(n)
+	if err != nil {
+		return written, fmt.Errorf("golnk.ExtraDataBlock.WriteTo: write payload - %s", err.Error())
+	}
+	return written, nil
+}
+
+// WriteTo serializes the section back into its on-disk form: each block in
+// turn, followed by the 4-byte TerminalBlock.
+func (e ExtraDataSection) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for _, b := range e.Blocks() {
+		n, err := b.WriteTo(w)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	if err := binary.Write(w, binary.LittleEndian, e.TerminalBlock); err != nil {
+		return written, fmt.Errorf("golnk.ExtraDataSection.WriteTo: write terminal block - %s", err.Error())
+	}
+	written += 4
+	return written, nil
+}
@@ -0,0 +1,84 @@
+package lnk
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestScanExtraDataSectionRejectsOversizedBlock(t *testing.T) {
+	data := []byte{
+		0xFF, 0xFF, 0xFF, 0xFF, // Size = 0xFFFFFFFF
+		0x02, 0x00, 0x00, 0xA0, // Signature = 0xA0000002 (ConsoleDataBlock)
+	}
+	if _, err := scanExtraDataSection(bytes.NewReader(data), 0); err == nil {
+		t.Fatal("expected an error for an oversized block Size, got nil")
+	}
+}
+
+func TestScanExtraDataSectionRejectsUnderflowingBlock(t *testing.T) {
+	data := []byte{
+		0x05, 0x00, 0x00, 0x00, // Size = 5 (smaller than the 8-byte header)
+		0x02, 0x00, 0x00, 0xA0, // Signature = 0xA0000002 (ConsoleDataBlock)
+	}
+	if _, err := scanExtraDataSection(bytes.NewReader(data), 0); err == nil {
+		t.Fatal("expected an error for a block Size that underflows size-8, got nil")
+	}
+}
+
+func TestExtraDataSectionWriteToRoundTrip(t *testing.T) {
+	section := ExtraDataSection{
+		entries: []ExtraDataBlock{
+			{
+				Signature: 0xA0000003,
+				Type:      "TrackerDataBlock",
+				Object: &TrackerDataBlock{
+					Length:    88,
+					Version:   0,
+					MachineID: "HOST",
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if _, err := section.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	got, err := DataBlock(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("DataBlock: %v", err)
+	}
+	blk := got.BlockByType("TrackerDataBlock")
+	if blk == nil {
+		t.Fatal("TrackerDataBlock not found after round trip")
+	}
+	db, ok := blk.Object.(*TrackerDataBlock)
+	if !ok {
+		t.Fatalf("Object is %T, want *TrackerDataBlock", blk.Object)
+	}
+	if db.MachineID != "HOST" {
+		t.Errorf("MachineID = %q, want %q", db.MachineID, "HOST")
+	}
+
+	// Mutating a typed field and re-serializing should round-trip the change.
+	db.MachineID = "OTHERHOST"
+	got.entries[0].Object = db
+
+	var buf2 bytes.Buffer
+	if _, err := got.WriteTo(&buf2); err != nil {
+		t.Fatalf("WriteTo after mutation: %v", err)
+	}
+	reread, err := DataBlock(bytes.NewReader(buf2.Bytes()))
+	if err != nil {
+		t.Fatalf("DataBlock after mutation: %v", err)
+	}
+	blk2 := reread.BlockByType("TrackerDataBlock")
+	if blk2 == nil {
+		t.Fatal("TrackerDataBlock not found after second round trip")
+	}
+	db2 := blk2.Object.(*TrackerDataBlock)
+	if db2.MachineID != "OTHERHOST" {
+		t.Errorf("MachineID after mutation = %q, want %q", db2.MachineID, "OTHERHOST")
+	}
+}
@@ -0,0 +1,337 @@
+package lnk
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ShellLinkHeader is the fixed 76-byte header found at the start of every
+// .lnk file (MS-SHLLNK section 2.1).
+type ShellLinkHeader struct {
+	HeaderSize     uint32
+	LinkCLSID      GUID
+	LinkFlags      uint32
+	FileAttributes uint32
+	CreationTime   uint64
+	AccessTime     uint64
+	WriteTime      uint64
+	FileSize       uint32
+	IconIndex      int32
+	ShowCommand    uint32
+	HotKey         uint16
+	Reserved1      uint16
+	Reserved2      uint32
+	Reserved3      uint32
+}
+
+// LinkFlags bits (MS-SHLLNK section 2.1.1) that determine which optional
+// sections follow the ShellLinkHeader.
+const (
+	linkFlagHasLinkTargetIDList = 1 << iota
+	linkFlagHasLinkInfo
+	linkFlagHasName
+	linkFlagHasRelativePath
+	linkFlagHasWorkingDir
+	linkFlagHasArguments
+	linkFlagHasIconLocation
+	linkFlagIsUnicode
+)
+
+func (h *ShellLinkHeader) decode(b []byte) error {
+	if len(b) < 76 {
+		return fmt.Errorf("short header (%d bytes)", len(b))
+	}
+	h.HeaderSize = binary.LittleEndian.Uint32(b[0:4])
+	h.LinkCLSID = readGUID(b[4:20])
+	h.LinkFlags = binary.LittleEndian.Uint32(b[20:24])
+	h.FileAttributes = binary.LittleEndian.Uint32(b[24:28])
+	h.CreationTime = binary.LittleEndian.Uint64(b[28:36])
+	h.AccessTime = binary.LittleEndian.Uint64(b[36:44])
+	h.WriteTime = binary.LittleEndian.Uint64(b[44:52])
+	h.FileSize = binary.LittleEndian.Uint32(b[52:56])
+	h.IconIndex = int32(binary.LittleEndian.Uint32(b[56:60]))
+	h.ShowCommand = binary.LittleEndian.Uint32(b[60:64])
+	h.HotKey = binary.LittleEndian.Uint16(b[64:66])
+	h.Reserved1 = binary.LittleEndian.Uint16(b[66:68])
+	h.Reserved2 = binary.LittleEndian.Uint32(b[68:72])
+	h.Reserved3 = binary.LittleEndian.Uint32(b[72:76])
+	return nil
+}
+
+// MarshalJSON renders the header with LinkCLSID as a canonical GUID string
+// and CreationTime/AccessTime/WriteTime as RFC3339 UTC strings rather than
+// raw FILETIME ticks.
+func (h ShellLinkHeader) MarshalJSON() ([]byte, error) {
+	out := struct {
+		HeaderSize     uint32    `json:"header_size"`
+		LinkCLSID      GUID      `json:"link_clsid"`
+		LinkFlags      uint32    `json:"link_flags"`
+		FileAttributes uint32    `json:"file_attributes"`
+		CreationTime   time.Time `json:"creation_time"`
+		AccessTime     time.Time `json:"access_time"`
+		WriteTime      time.Time `json:"write_time"`
+		FileSize       uint32    `json:"file_size"`
+		IconIndex      int32     `json:"icon_index"`
+		ShowCommand    uint32    `json:"show_command"`
+		HotKey         uint16    `json:"hot_key"`
+	}{
+		HeaderSize:     h.HeaderSize,
+		LinkCLSID:      h.LinkCLSID,
+		LinkFlags:      h.LinkFlags,
+		FileAttributes: h.FileAttributes,
+		CreationTime:   filetimeToTime(h.CreationTime),
+		AccessTime:     filetimeToTime(h.AccessTime),
+		WriteTime:      filetimeToTime(h.WriteTime),
+		FileSize:       h.FileSize,
+		IconIndex:      h.IconIndex,
+		ShowCommand:    h.ShowCommand,
+		HotKey:         h.HotKey,
+	}
+	return json.Marshal(out)
+}
+
+// File represents an open .lnk file, modeled on debug/elf.File. NewFile
+// reads the ShellLinkHeader, LinkTargetIDList, LinkInfo, and StringData up
+// front; only the ExtraData section is scanned lazily, on the first call to
+// ExtraData, and no block's Object is decoded until it is looked up, so
+// callers can pull a single block (say, TrackerDataBlock) out of a large
+// directory of shortcuts without decoding everything in between.
+//
+// LinkTargetIDList and LinkInfo are kept as their raw on-disk bytes; this
+// package does not yet decode their internal structure (MS-SHLLNK sections
+// 2.2, 2.3). The StringData strings are simple length-prefixed text and are
+// decoded fully.
+type File struct {
+	Header ShellLinkHeader
+
+	LinkTargetIDList []byte
+	LinkInfo         []byte
+
+	// StringData fields are nil when the corresponding LinkFlags bit is not
+	// set (MS-SHLLNK section 2.4).
+	NameString           *string
+	RelativePath         *string
+	WorkingDir           *string
+	CommandLineArguments *string
+	IconLocation         *string
+
+	r        io.ReaderAt
+	closer   io.Closer
+	extraOff int64
+	extra    *ExtraDataSection
+}
+
+// Open opens the named .lnk file and prepares it for lazy section access.
+// The caller should call Close when finished with the File.
+func Open(name string) (*File, error) {
+	osf, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := NewFile(osf)
+	if err != nil {
+		osf.Close()
+		return nil, err
+	}
+	f.closer = osf
+	return f, nil
+}
+
+// Close closes the File's backing os.File, if it was opened via Open. It is
+// a no-op for a File created directly with NewFile.
+func (f *File) Close() error {
+	if f.closer != nil {
+		return f.closer.Close()
+	}
+	return nil
+}
+
+// NewFile parses the fixed ShellLinkHeader, LinkTargetIDList, LinkInfo, and
+// StringData strings from r, then locates -- without decoding -- the
+// ExtraData section that follows them.
+func NewFile(r io.ReaderAt) (*File, error) {
+	f := &File{r: r}
+
+	hdr := make([]byte, 76)
+	if _, err := r.ReadAt(hdr, 0); err != nil {
+		return nil, fmt.Errorf("golnk.NewFile: read header - %s", err.Error())
+	}
+	if err := f.Header.decode(hdr); err != nil {
+		return nil, fmt.Errorf("golnk.NewFile: %s", err.Error())
+	}
+
+	off := int64(f.Header.HeaderSize)
+	if f.Header.LinkFlags&linkFlagHasLinkTargetIDList != 0 {
+		raw, err := readLinkTargetIDList(r, off)
+		if err != nil {
+			return nil, fmt.Errorf("golnk.NewFile: %s", err.Error())
+		}
+		f.LinkTargetIDList = raw
+		off += 2 + int64(len(raw))
+	}
+	if f.Header.LinkFlags&linkFlagHasLinkInfo != 0 {
+		raw, err := readLinkInfo(r, off)
+		if err != nil {
+			return nil, fmt.Errorf("golnk.NewFile: %s", err.Error())
+		}
+		f.LinkInfo = raw
+		off += int64(len(raw))
+	}
+
+	unicode := f.Header.LinkFlags&linkFlagIsUnicode != 0
+	for _, field := range []struct {
+		present bool
+		dst     **string
+	}{
+		{f.Header.LinkFlags&linkFlagHasName != 0, &f.NameString},
+		{f.Header.LinkFlags&linkFlagHasRelativePath != 0, &f.RelativePath},
+		{f.Header.LinkFlags&linkFlagHasWorkingDir != 0, &f.WorkingDir},
+		{f.Header.LinkFlags&linkFlagHasArguments != 0, &f.CommandLineArguments},
+		{f.Header.LinkFlags&linkFlagHasIconLocation != 0, &f.IconLocation},
+	} {
+		if !field.present {
+			continue
+		}
+		s, n, err := readStringData(r, off, unicode)
+		if err != nil {
+			return nil, fmt.Errorf("golnk.NewFile: %s", err.Error())
+		}
+		*field.dst = &s
+		off += n
+	}
+
+	f.extraOff = off
+	return f, nil
+}
+
+// ExtraData lazily parses and returns the file's ExtraDataSection, scanning
+// it (without decoding any block's Object) on first access.
+func (f *File) ExtraData() (*ExtraDataSection, error) {
+	if f.extra == nil {
+		extra, err := scanExtraDataSection(f.r, f.extraOff)
+		if err != nil {
+			return nil, err
+		}
+		f.extra = &extra
+	}
+	return f.extra, nil
+}
+
+// MarshalJSON produces a single object spanning the header, the raw
+// LinkTargetIDList/LinkInfo sections, the decoded StringData strings, and
+// the (lazily decoded) ExtraData section.
+func (f *File) MarshalJSON() ([]byte, error) {
+	extra, err := f.ExtraData()
+	if err != nil {
+		return nil, fmt.Errorf("golnk.File.MarshalJSON: %s", err.Error())
+	}
+
+	out := struct {
+		Header               ShellLinkHeader  `json:"header"`
+		LinkTargetIDList     []byte           `json:"link_target_id_list,omitempty"`
+		LinkInfo             []byte           `json:"link_info,omitempty"`
+		NameString           *string          `json:"name_string,omitempty"`
+		RelativePath         *string          `json:"relative_path,omitempty"`
+		WorkingDir           *string          `json:"working_dir,omitempty"`
+		CommandLineArguments *string          `json:"command_line_arguments,omitempty"`
+		IconLocation         *string          `json:"icon_location,omitempty"`
+		ExtraData            ExtraDataSection `json:"extra_data"`
+	}{
+		Header:               f.Header,
+		LinkTargetIDList:     f.LinkTargetIDList,
+		LinkInfo:             f.LinkInfo,
+		NameString:           f.NameString,
+		RelativePath:         f.RelativePath,
+		WorkingDir:           f.WorkingDir,
+		CommandLineArguments: f.CommandLineArguments,
+		IconLocation:         f.IconLocation,
+		ExtraData:            *extra,
+	}
+	return json.Marshal(out)
+}
+
+// readLinkTargetIDList reads the LinkTargetIDList structure at off -- a
+// uint16 IDListSize followed by that many bytes -- and returns those
+// IDListSize bytes (MS-SHLLNK section 2.2).
+func readLinkTargetIDList(r io.ReaderAt, off int64) ([]byte, error) {
+	var sizeBuf [2]byte
+	if _, err := r.ReadAt(sizeBuf[:], off); err != nil {
+		return nil, fmt.Errorf("read LinkTargetIDList size - %s", err.Error())
+	}
+	size := binary.LittleEndian.Uint16(sizeBuf[:])
+	buf := make([]byte, size)
+	if len(buf) > 0 {
+		if _, err := r.ReadAt(buf, off+2); err != nil {
+			return nil, fmt.Errorf("read LinkTargetIDList - %s", err.Error())
+		}
+	}
+	return buf, nil
+}
+
+// maxLinkInfoSize bounds the allocation readLinkInfo makes for the
+// attacker-controlled LinkInfoSize field, guarding against a crafted .lnk
+// with a size near 0xFFFFFFFF forcing a multi-gigabyte allocation before the
+// subsequent read even has a chance to fail. Real LinkInfo structures are at
+// most a few KiB (two MAX_PATH-ish strings plus a small fixed header).
+const maxLinkInfoSize = 1 << 20 // 1 MiB.
+
+// readLinkInfo reads the LinkInfo structure at off, which starts with a
+// uint32 LinkInfoSize that includes itself, and returns the whole structure
+// (MS-SHLLNK section 2.3).
+func readLinkInfo(r io.ReaderAt, off int64) ([]byte, error) {
+	var sizeBuf [4]byte
+	if _, err := r.ReadAt(sizeBuf[:], off); err != nil {
+		return nil, fmt.Errorf("read LinkInfo size - %s", err.Error())
+	}
+	size := binary.LittleEndian.Uint32(sizeBuf[:])
+	if size < 4 {
+		return nil, fmt.Errorf("LinkInfo size 0x%X is smaller than its own 4-byte size field", size)
+	}
+	if size > maxLinkInfoSize {
+		return nil, fmt.Errorf("LinkInfo size 0x%X exceeds sanity limit of 0x%X", size, uint32(maxLinkInfoSize))
+	}
+	buf := make([]byte, size)
+	if _, err := r.ReadAt(buf, off); err != nil {
+		return nil, fmt.Errorf("read LinkInfo - %s", err.Error())
+	}
+	return buf, nil
+}
+
+// readStringData reads one StringData string at off: a uint16 character
+// count, followed by that many UTF-16LE code units (unicode true) or ANSI
+// bytes, and returns the decoded string along with its on-disk byte length
+// (MS-SHLLNK section 2.4).
+func readStringData(r io.ReaderAt, off int64, unicode bool) (string, int64, error) {
+	var countBuf [2]byte
+	if _, err := r.ReadAt(countBuf[:], off); err != nil {
+		return "", 0, fmt.Errorf("read StringData count - %s", err.Error())
+	}
+	count := int64(binary.LittleEndian.Uint16(countBuf[:]))
+
+	charBytes := count
+	if unicode {
+		charBytes *= 2
+	}
+	buf := make([]byte, charBytes)
+	if charBytes > 0 {
+		if _, err := r.ReadAt(buf, off+2); err != nil {
+			return "", 0, fmt.Errorf("read StringData - %s", err.Error())
+		}
+	}
+
+	var s string
+	if unicode {
+		u := make([]uint16, count)
+		for i := range u {
+			u[i] = binary.LittleEndian.Uint16(buf[i*2 : i*2+2])
+		}
+		s = utf16ToString(u)
+	} else {
+		s = string(buf)
+	}
+	return s, 2 + charBytes, nil
+}
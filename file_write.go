@@ -0,0 +1,178 @@
+package lnk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// WriteTo serializes the header back into its on-disk 76-byte form.
+func (h ShellLinkHeader) WriteTo(w io.Writer) (int64, error) {
+	buf := make([]byte, 76)
+	binary.LittleEndian.PutUint32(buf[0:4], h.HeaderSize)
+	copy(buf[4:20], h.LinkCLSID[:])
+	binary.LittleEndian.PutUint32(buf[20:24], h.LinkFlags)
+	binary.LittleEndian.PutUint32(buf[24:28], h.FileAttributes)
+	binary.LittleEndian.PutUint64(buf[28:36], h.CreationTime)
+	binary.LittleEndian.PutUint64(buf[36:44], h.AccessTime)
+	binary.LittleEndian.PutUint64(buf[44:52], h.WriteTime)
+	binary.LittleEndian.PutUint32(buf[52:56], h.FileSize)
+	binary.LittleEndian.PutUint32(buf[56:60], uint32(h.IconIndex))
+	binary.LittleEndian.PutUint32(buf[60:64], h.ShowCommand)
+	binary.LittleEndian.PutUint16(buf[64:66], h.HotKey)
+	binary.LittleEndian.PutUint16(buf[66:68], h.Reserved1)
+	binary.LittleEndian.PutUint32(buf[68:72], h.Reserved2)
+	binary.LittleEndian.PutUint32(buf[72:76], h.Reserved3)
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// Write serializes f back into its on-disk .lnk form: the ShellLinkHeader,
+// followed by whichever of LinkTargetIDList, LinkInfo, and the StringData
+// strings are present, followed by ExtraData.
+//
+// HeaderSize and LinkFlags are recomputed from f's fields before writing, so
+// e.g. setting IconLocation to nil and calling Write omits IconLocation and
+// clears linkFlagHasIconLocation rather than leaving them stale. FileSize is
+// a property of the link target, not of the .lnk file itself (MS-SHLLNK
+// section 2.1), so it is written unchanged from f.Header.FileSize.
+func (f *File) Write(w io.Writer) (int64, error) {
+	hdr := f.Header
+	hdr.HeaderSize = 0x4C // MS-SHLLNK section 2.1: always 76 bytes.
+	hdr.LinkFlags = f.linkFlags()
+
+	var written int64
+	n, err := hdr.WriteTo(w)
+	written += n
+	if err != nil {
+		return written, fmt.Errorf("golnk.File.Write: write header - %s", err.Error())
+	}
+
+	if hdr.LinkFlags&linkFlagHasLinkTargetIDList != 0 {
+		n, err := writeLinkTargetIDList(w, f.LinkTargetIDList)
+		written += n
+		if err != nil {
+			return written, fmt.Errorf("golnk.File.Write: write LinkTargetIDList - %s", err.Error())
+		}
+	}
+	if hdr.LinkFlags&linkFlagHasLinkInfo != 0 {
+		n, err := w.Write(f.LinkInfo)
+		written += int64(n)
+		if err != nil {
+			return written, fmt.Errorf("golnk.File.Write: write LinkInfo - %s", err.Error())
+		}
+	}
+
+	unicode := hdr.LinkFlags&linkFlagIsUnicode != 0
+	for _, s := range []*string{f.NameString, f.RelativePath, f.WorkingDir, f.CommandLineArguments, f.IconLocation} {
+		if s == nil {
+			continue
+		}
+		n, err := writeStringData(w, *s, unicode)
+		written += n
+		if err != nil {
+			return written, fmt.Errorf("golnk.File.Write: write StringData - %s", err.Error())
+		}
+	}
+
+	extra, err := f.ExtraData()
+	if err != nil {
+		return written, fmt.Errorf("golnk.File.Write: %s", err.Error())
+	}
+	n, err = extra.WriteTo(w)
+	written += n
+	if err != nil {
+		return written, fmt.Errorf("golnk.File.Write: write ExtraData - %s", err.Error())
+	}
+	return written, nil
+}
+
+// linkFlags recomputes the section-presence bits of Header.LinkFlags from
+// f's fields, preserving every other bit (e.g. linkFlagIsUnicode) from the
+// original header.
+func (f *File) linkFlags() uint32 {
+	const presenceMask = linkFlagHasLinkTargetIDList | linkFlagHasLinkInfo |
+		linkFlagHasName | linkFlagHasRelativePath | linkFlagHasWorkingDir |
+		linkFlagHasArguments | linkFlagHasIconLocation
+
+	flags := f.Header.LinkFlags &^ presenceMask
+	if len(f.LinkTargetIDList) > 0 {
+		flags |= linkFlagHasLinkTargetIDList
+	}
+	if len(f.LinkInfo) > 0 {
+		flags |= linkFlagHasLinkInfo
+	}
+	if f.NameString != nil {
+		flags |= linkFlagHasName
+	}
+	if f.RelativePath != nil {
+		flags |= linkFlagHasRelativePath
+	}
+	if f.WorkingDir != nil {
+		flags |= linkFlagHasWorkingDir
+	}
+	if f.CommandLineArguments != nil {
+		flags |= linkFlagHasArguments
+	}
+	if f.IconLocation != nil {
+		flags |= linkFlagHasIconLocation
+	}
+	return flags
+}
+
+// writeLinkTargetIDList writes the LinkTargetIDList structure: a uint16
+// IDListSize followed by idList itself (MS-SHLLNK section 2.2).
+func writeLinkTargetIDList(w io.Writer, idList []byte) (int64, error) {
+	var written int64
+	if err := binary.Write(w, binary.LittleEndian, uint16(len(idList))); err != nil {
+		return written, fmt.Errorf("write LinkTargetIDList size - %s", err.Error())
+	}
+	written += 2
+	n, err := w.Write(idList)
+	written += int64(n)
+	if err != nil {
+		return written, fmt.Errorf("write LinkTargetIDList - %s", err.Error())
+	}
+	return written, nil
+}
+
+// writeStringData writes one StringData string: a uint16 character count,
+// followed by that many UTF-16LE code units (unicode true) or ANSI bytes
+// (MS-SHLLNK section 2.4).
+func writeStringData(w io.Writer, s string, unicode bool) (int64, error) {
+	var written int64
+	var body []byte
+	var count uint16
+	if unicode {
+		body = encodeUnicodeStringNoPad(s)
+		count = uint16(len(body) / 2)
+	} else {
+		body = []byte(s)
+		count = uint16(len(body))
+	}
+	if err := binary.Write(w, binary.LittleEndian, count); err != nil {
+		return written, fmt.Errorf("write StringData count - %s", err.Error())
+	}
+	written += 2
+	n, err := w.Write(body)
+	written += int64(n)
+	if err != nil {
+		return written, fmt.Errorf("write StringData - %s", err.Error())
+	}
+	return written, nil
+}
+
+// WriteFile serializes f and writes it to the named path, creating the file
+// if necessary and truncating it if it already exists.
+func WriteFile(path string, f File) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("golnk.WriteFile: %s", err.Error())
+	}
+	if _, err := f.Write(out); err != nil {
+		out.Close()
+		return fmt.Errorf("golnk.WriteFile: %s", err.Error())
+	}
+	return out.Close()
+}
@@ -0,0 +1,38 @@
+package lnk
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFileWriteRoundTrip(t *testing.T) {
+	name := "example"
+	extra := ExtraDataSection{}
+	f := &File{
+		Header: ShellLinkHeader{
+			LinkFlags:   linkFlagHasName | linkFlagIsUnicode,
+			ShowCommand: 1,
+		},
+		NameString: &name,
+		extra:      &extra,
+	}
+
+	var buf bytes.Buffer
+	if _, err := f.Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	got, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewFile: %v", err)
+	}
+	if got.Header.HeaderSize != 0x4C {
+		t.Errorf("HeaderSize = 0x%X, want 0x4C", got.Header.HeaderSize)
+	}
+	if got.Header.LinkFlags&linkFlagHasName == 0 {
+		t.Error("LinkFlags missing linkFlagHasName")
+	}
+	if got.NameString == nil || *got.NameString != name {
+		t.Errorf("NameString = %v, want %q", got.NameString, name)
+	}
+}
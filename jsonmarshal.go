@@ -0,0 +1,137 @@
+package lnk
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalJSON renders the GUID in its canonical
+// "{XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX}" form.
+func (g GUID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.String())
+}
+
+// MarshalJSON renders the block's signature as both the hex constant and
+// its symbolic Type name, and the payload as the decoded Object when one
+// exists or as base64-encoded raw Data otherwise.
+func (db ExtraDataBlock) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Size      uint32      `json:"size"`
+		Signature string      `json:"signature"`
+		Type      string      `json:"type"`
+		Object    interface{} `json:"object,omitempty"`
+		Data      []byte      `json:"data,omitempty"`
+	}{
+		Size:      db.Size,
+		Signature: fmt.Sprintf("0x%08X", db.Signature),
+		Type:      db.Type,
+	}
+	if db.Object != nil {
+		out.Object = db.Object
+	} else {
+		out.Data = db.Data
+	}
+	return json.Marshal(out)
+}
+
+// MarshalJSON renders every block, decoding (and caching) each one's Object
+// as Blocks does.
+func (e ExtraDataSection) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Blocks        []ExtraDataBlock `json:"blocks"`
+		TerminalBlock uint32           `json:"terminal_block"`
+	}{
+		Blocks:        e.Blocks(),
+		TerminalBlock: e.TerminalBlock,
+	}
+	return json.Marshal(out)
+}
+
+// MarshalJSON renders FaceName as its decoded string rather than the raw
+// [32]uint16 buffer.
+func (db ConsoleDataBlock) MarshalJSON() ([]byte, error) {
+	out := struct {
+		FillAttributes         uint16     `json:"fill_attributes"`
+		PopupFillAttributes    uint16     `json:"popup_fill_attributes"`
+		ScreenBufferSize       COORD      `json:"screen_buffer_size"`
+		WindowSize             COORD      `json:"window_size"`
+		WindowOrigin           COORD      `json:"window_origin"`
+		FontSize               uint32     `json:"font_size"`
+		FontFamily             uint32     `json:"font_family"`
+		FontWeight             uint32     `json:"font_weight"`
+		FaceName               string     `json:"face_name"`
+		CursorSize             uint32     `json:"cursor_size"`
+		FullScreen             uint32     `json:"full_screen"`
+		QuickEdit              uint32     `json:"quick_edit"`
+		InsertMode             uint32     `json:"insert_mode"`
+		AutoPosition           uint32     `json:"auto_position"`
+		HistoryBufferSize      uint32     `json:"history_buffer_size"`
+		NumberOfHistoryBuffers uint32     `json:"number_of_history_buffers"`
+		HistoryNoDup           uint32     `json:"history_no_dup"`
+		ColorTable             [16]uint32 `json:"color_table"`
+	}{
+		FillAttributes:         db.FillAttributes,
+		PopupFillAttributes:    db.PopupFillAttributes,
+		ScreenBufferSize:       db.ScreenBufferSize,
+		WindowSize:             db.WindowSize,
+		WindowOrigin:           db.WindowOrigin,
+		FontSize:               db.FontSize,
+		FontFamily:             db.FontFamily,
+		FontWeight:             db.FontWeight,
+		FaceName:               db.FaceNameString(),
+		CursorSize:             db.CursorSize,
+		FullScreen:             db.FullScreen,
+		QuickEdit:              db.QuickEdit,
+		InsertMode:             db.InsertMode,
+		AutoPosition:           db.AutoPosition,
+		HistoryBufferSize:      db.HistoryBufferSize,
+		NumberOfHistoryBuffers: db.NumberOfHistoryBuffers,
+		HistoryNoDup:           db.HistoryNoDup,
+		ColorTable:             db.ColorTable,
+	}
+	return json.Marshal(out)
+}
+
+// vtName returns the symbolic VT_* name for a MS-OLEPS variant type tag.
+func vtName(vt uint16) string {
+	switch vt {
+	case vtEmpty:
+		return "VT_EMPTY"
+	case vtI2:
+		return "VT_I2"
+	case vtI4:
+		return "VT_I4"
+	case vtBool:
+		return "VT_BOOL"
+	case vtUI4:
+		return "VT_UI4"
+	case vtUI8:
+		return "VT_UI8"
+	case vtFiletime:
+		return "VT_FILETIME"
+	case vtClsid:
+		return "VT_CLSID"
+	case vtLPWSTR:
+		return "VT_LPWSTR"
+	default:
+		return fmt.Sprintf("VT_UNKNOWN(0x%04X)", vt)
+	}
+}
+
+// MarshalJSON tags Value with its symbolic VT_* variant type name, and
+// base64-encodes Value when it is the raw-fallback []byte produced for a
+// VarType with no decoder.
+func (v SerializedPropertyValue) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Name    string      `json:"name,omitempty"`
+		ID      uint32      `json:"id,omitempty"`
+		VarType string      `json:"var_type"`
+		Value   interface{} `json:"value"`
+	}{
+		Name:    v.Name,
+		ID:      v.ID,
+		VarType: vtName(v.VarType),
+		Value:   v.Value,
+	}
+	return json.Marshal(out)
+}
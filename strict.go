@@ -0,0 +1,196 @@
+package lnk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// ParseOptions controls how DataBlockWithOptions handles malformed or
+// adversarial ExtraData. The zero value is permissive: malformed blocks are
+// recorded as ParseWarnings and parsing resyncs to the next recognizable
+// block rather than aborting. Set Strict to abort on the first error
+// instead.
+type ParseOptions struct {
+	// Strict aborts parsing on the first error instead of recording a
+	// ParseWarning. The zero value, false, is permissive: malformed blocks are
+	// recorded as ParseWarnings instead, and parsing resyncs to the next
+	// recognizable block signature rather than failing outright.
+	Strict bool
+	// MaxBlockSize rejects any block whose Size field exceeds it, guarding
+	// against a corrupt or adversarial .lnk file with a size field near
+	// 0xFFFFFFFF that would otherwise drive a huge allocation. Zero means
+	// no limit.
+	MaxBlockSize uint32
+	// MaxBlocks caps the number of blocks read from the section. Zero means
+	// no limit.
+	MaxBlocks int
+	// AllowUnknown accepts signatures outside the known blockSignature map.
+	// When false, an unrecognized signature is treated as malformed.
+	AllowUnknown bool
+}
+
+// ParseWarning describes a non-fatal problem encountered while parsing an
+// ExtraDataSection in non-strict mode: a block was malformed, oversized, of
+// an unknown signature, or a configured limit was hit. After a malformed,
+// oversized, or unknown-signature block, parsing resyncs to the next
+// recognizable block; a block whose typed Object fails to decode is kept
+// with Object left nil; exceeding MaxBlocks or a truncated size field ends
+// parsing.
+type ParseWarning struct {
+	Offset    int64
+	Signature uint32
+	Err       error
+}
+
+func (w ParseWarning) String() string {
+	return fmt.Sprintf("offset 0x%X: signature 0x%08X: %s", w.Offset, w.Signature, w.Err)
+}
+
+// DataBlockWithOptions parses an ExtraDataSection per opts. With opts.Strict
+// set, it aborts on the first error, including an unknown signature (unless
+// opts.AllowUnknown is set) and a typed-decode failure, both of which
+// DataBlock tolerates. In
+// the permissive zero-value mode, malformed blocks are recorded as
+// ParseWarnings rather than aborting the scan, and parsing resyncs to the
+// next recognizable block signature, so a single corrupt PropertyStore
+// doesn't hide a valid TrackerDataBlock that follows it.
+func DataBlockWithOptions(r io.Reader, opts ParseOptions) (ExtraDataSection, []ParseWarning, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return ExtraDataSection{}, nil, fmt.Errorf("golnk.DataBlockWithOptions: read - %s", err.Error())
+	}
+	return parseExtraDataSection(data, opts)
+}
+
+func parseExtraDataSection(data []byte, opts ParseOptions) (ExtraDataSection, []ParseWarning, error) {
+	var extra ExtraDataSection
+	var warnings []ParseWarning
+	off := int64(0)
+
+	// warn records a ParseWarning and reports whether parsing should
+	// continue. In strict mode it records nothing and returns false, so the
+	// caller aborts with err instead.
+	warn := func(offset int64, sig uint32, err error) bool {
+		if opts.Strict {
+			return false
+		}
+		warnings = append(warnings, ParseWarning{Offset: offset, Signature: sig, Err: err})
+		return true
+	}
+
+	for {
+		if off+4 > int64(len(data)) {
+			if off == int64(len(data)) {
+				// No TerminalBlock, but also no trailing garbage: treat as
+				// an empty/absent terminator rather than an error.
+				return extra, warnings, nil
+			}
+			err := fmt.Errorf("unexpected EOF reading block size")
+			if !warn(off, 0, err) {
+				return extra, warnings, fmt.Errorf("golnk.DataBlockWithOptions: %s", err)
+			}
+			return extra, warnings, nil
+		}
+
+		size := binary.LittleEndian.Uint32(data[off : off+4])
+		if size < 0x04 {
+			extra.TerminalBlock = size
+			return extra, warnings, nil
+		}
+		if opts.MaxBlocks > 0 && len(extra.entries) >= opts.MaxBlocks {
+			err := fmt.Errorf("block count exceeds MaxBlocks limit (%d)", opts.MaxBlocks)
+			if !warn(off, 0, err) {
+				return extra, warnings, fmt.Errorf("golnk.DataBlockWithOptions: %s", err)
+			}
+			return extra, warnings, nil
+		}
+
+		switch {
+		case size < 8:
+			err := fmt.Errorf("block size 0x%X is smaller than the 8-byte block header", size)
+			if !warn(off, 0, err) {
+				return extra, warnings, fmt.Errorf("golnk.DataBlockWithOptions: %s", err)
+			}
+			next := resyncToNextBlock(data, off+5)
+			if next < 0 {
+				return extra, warnings, nil
+			}
+			off = next
+			continue
+
+		case opts.MaxBlockSize > 0 && size > opts.MaxBlockSize:
+			err := fmt.Errorf("block size 0x%X exceeds MaxBlockSize 0x%X", size, opts.MaxBlockSize)
+			if !warn(off, 0, err) {
+				return extra, warnings, fmt.Errorf("golnk.DataBlockWithOptions: %s", err)
+			}
+			next := resyncToNextBlock(data, off+5)
+			if next < 0 {
+				return extra, warnings, nil
+			}
+			off = next
+			continue
+
+		case off+int64(size) > int64(len(data)):
+			err := fmt.Errorf("block size 0x%X exceeds %d remaining bytes", size, int64(len(data))-off)
+			if !warn(off, 0, err) {
+				return extra, warnings, fmt.Errorf("golnk.DataBlockWithOptions: %s", err)
+			}
+			next := resyncToNextBlock(data, off+5)
+			if next < 0 {
+				return extra, warnings, nil
+			}
+			off = next
+			continue
+		}
+
+		sig := binary.LittleEndian.Uint32(data[off+4 : off+8])
+		if _, known := blockSignatureNames[sig]; !known && !opts.AllowUnknown {
+			err := fmt.Errorf("unknown signature 0x%08X", sig)
+			if !warn(off, sig, err) {
+				return extra, warnings, fmt.Errorf("golnk.DataBlockWithOptions: %s", err)
+			}
+			next := resyncToNextBlock(data, off+5)
+			if next < 0 {
+				return extra, warnings, nil
+			}
+			off = next
+			continue
+		}
+
+		block := ExtraDataBlock{
+			Size:      size,
+			Signature: sig,
+			Type:      blockSignature(sig),
+			Data:      append([]byte(nil), data[off+8:off+int64(size)]...),
+		}
+		obj, err := decodeExtraDataBlockObject(sig, block.Data)
+		if err != nil {
+			if !warn(off, sig, err) {
+				return extra, warnings, fmt.Errorf("golnk.DataBlockWithOptions: decode %s - %s", block.Type, err.Error())
+			}
+			// The block framing itself was sound; only the typed decode
+			// failed, so keep the block (with Object left nil) rather than
+			// discarding it or resyncing past it.
+		} else {
+			block.Object = obj
+		}
+		extra.entries = append(extra.entries, block)
+
+		off += int64(size)
+	}
+}
+
+// resyncToNextBlock scans data for the next 4-byte signature word matching
+// a known block signature, starting the search at sigSearchFrom, and
+// returns the offset of that candidate block's Size field (four bytes
+// before the signature). It returns -1 if no known signature is found.
+func resyncToNextBlock(data []byte, sigSearchFrom int64) int64 {
+	for pos := sigSearchFrom; pos+4 <= int64(len(data)); pos++ {
+		sig := binary.LittleEndian.Uint32(data[pos : pos+4])
+		if _, known := blockSignatureNames[sig]; known {
+			return pos - 4
+		}
+	}
+	return -1
+}
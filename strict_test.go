@@ -0,0 +1,43 @@
+package lnk
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDataBlockWithOptionsResync(t *testing.T) {
+	data := []byte{
+		// Block 1: malformed -- unknown signature, skipped with a warning.
+		0x0C, 0x00, 0x00, 0x00, // Size = 12
+		0xEF, 0xBE, 0xAD, 0xDE, // Signature = 0xDEADBEEF (unknown)
+		0x01, 0x02, 0x03, 0x04, // Data (4 bytes)
+
+		// Block 2: valid ConsoleFEDataBlock, recovered via resync.
+		0x0C, 0x00, 0x00, 0x00, // Size = 12
+		0x04, 0x00, 0x00, 0xA0, // Signature = 0xA0000004 (ConsoleFEDataBlock)
+		0xB5, 0x01, 0x00, 0x00, // CodePage = 437
+
+		// TerminalBlock.
+		0x00, 0x00, 0x00, 0x00,
+	}
+
+	extra, warnings, err := DataBlockWithOptions(bytes.NewReader(data), ParseOptions{})
+	if err != nil {
+		t.Fatalf("DataBlockWithOptions: %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("len(warnings) = %d, want 1 (%v)", len(warnings), warnings)
+	}
+
+	blk := extra.BlockByType("ConsoleFEDataBlock")
+	if blk == nil {
+		t.Fatal("ConsoleFEDataBlock not recovered after resync")
+	}
+	db, ok := blk.Object.(*ConsoleFEDataBlock)
+	if !ok {
+		t.Fatalf("Object is %T, want *ConsoleFEDataBlock", blk.Object)
+	}
+	if db.CodePage != 437 {
+		t.Errorf("CodePage = %d, want 437", db.CodePage)
+	}
+}